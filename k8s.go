@@ -1,14 +1,33 @@
 /*
 Package k8s is focused on the most common utility functions and structures needed for rapid applications development for Kubernetes. For more robust and substantial production applications consider using the Kubernetes packages directly. Many may prefer to simply pilfer from this package and paste code into their own.
+
+Since v0.2.0, KubeConfig and its nested types (Cluster, User, Context,
+AuthProvider, ExecConfig) marshal through `json:` tags and their own
+MarshalJSON/UnmarshalJSON rather than a bare `yaml:",inline"` tag on O.
+Behavior on the wire (and in kubeconfig files on disk) is unchanged, but
+code that embedded these types in its own struct and relied on O being
+populated by gopkg.in/yaml.v2's inline handling must switch to relying
+on the MarshalJSON/UnmarshalJSON methods instead.
 */
 package k8s
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/rest"
 	kyaml "sigs.k8s.io/yaml"
 )
 
@@ -50,56 +69,633 @@ func NormYAML(y []byte) ([]byte, error) {
 // KUBECONFIG API changes). If something new is added, it will always be
 // available under O until (and if) it is graduated to having its own
 // reference. See the client-go types under clientcmd/api for more.
+//
+// O (here and on Cluster, User, Context, AuthProvider, and ExecConfig)
+// is tagged `json:"-"` and populated by this type's own MarshalJSON/
+// UnmarshalJSON rather than a struct tag: encoding/json has no
+// equivalent to yaml.v2's `yaml:",inline"`, which O relied on before
+// v0.2.0. Round-tripping through Load/Write/String is unaffected.
 type KubeConfig struct {
-	Clusters []*NCluster    `yaml:"clusters,omitempty"`
-	Contexts []*NContext    `yaml:"contexts,omitempty"`
-	Users    []*NUser       `yaml:"users,omitempty"` // AuthInfos
-	Current  string         `yaml:"current-context,omitempty"`
-	O        map[string]any `yaml:",inline,omitempty"`
+	Clusters []*NCluster    `json:"clusters,omitempty"`
+	Contexts []*NContext    `json:"contexts,omitempty"`
+	Users    []*NUser       `json:"users,omitempty"` // AuthInfos
+	Current  string         `json:"current-context,omitempty"`
+	O        map[string]any `json:"-"`
 }
 
-// Load configuration from a specific file at path.
+// Load configuration from a specific file at path. Load (and String and
+// Write) route through sigs.k8s.io/yaml rather than gopkg.in/yaml.v2 so
+// that this type's `json:` tags are honored and []byte fields are
+// base64-encoded the way kubectl and the rest of the Kubernetes
+// ecosystem expect.
 func (c *KubeConfig) Load(path string) error {
 	buf, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(buf, c)
+	return kyaml.Unmarshal(buf, c)
 }
 
 func (c KubeConfig) String() string {
-	buf, _ := yaml.Marshal(c)
+	buf, _ := kyaml.Marshal(c)
 	return string(buf)
 }
 
 func (c *KubeConfig) Write(path string) error {
-	buf, err := yaml.Marshal(c)
+	buf, err := kyaml.Marshal(c)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, buf, 0600)
 }
 
+// jsonFieldNames returns the top-level JSON tag names declared on the
+// exported fields of v's struct type, used by the inline* helpers below
+// to know which keys in a decoded map belong to named fields versus the
+// O catch-all.
+func jsonFieldNames(v any) map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// marshalInline is shared by the types below to approximate the
+// `yaml:",inline"` behavior encoding/json has no equivalent for: it
+// marshals v normally, then merges the keys of extra (an O field)
+// alongside the named fields rather than nesting them.
+func marshalInline(v any, extra map[string]any) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) == 0 {
+		return buf, nil
+	}
+	merged := map[string]any{}
+	if err := json.Unmarshal(buf, &merged); err != nil {
+		return nil, err
+	}
+	for k, val := range extra {
+		if _, exists := merged[k]; !exists {
+			merged[k] = val
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// unmarshalInline is the inverse of marshalInline: it fills v's named
+// fields from data and returns whatever top-level keys of data are not
+// among v's JSON tags, for the caller to stash in its O field.
+func unmarshalInline(data []byte, v any) (map[string]any, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	raw := map[string]any{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for name := range jsonFieldNames(reflect.ValueOf(v).Elem().Interface()) {
+		delete(raw, name)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// MarshalJSON implements json.Marshaler, inlining O alongside Clusters,
+// Contexts, Users, and Current rather than nesting it.
+func (c KubeConfig) MarshalJSON() ([]byte, error) {
+	type alias KubeConfig
+	return marshalInline(alias(c), c.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting any keys beyond
+// Clusters, Contexts, Users, and Current into O.
+func (c *KubeConfig) UnmarshalJSON(data []byte) error {
+	type alias KubeConfig
+	a := alias(*c)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*c = KubeConfig(a)
+	c.O = extra
+	return nil
+}
+
+// findCluster returns the NCluster named name, or nil.
+func (c *KubeConfig) findCluster(name string) *NCluster {
+	for _, n := range c.Clusters {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// findUser returns the NUser named name, or nil.
+func (c *KubeConfig) findUser(name string) *NUser {
+	for _, n := range c.Users {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// findContext returns the NContext named name, or nil.
+func (c *KubeConfig) findContext(name string) *NContext {
+	for _, n := range c.Contexts {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Minify reduces c to only the cluster, user, and context referenced by
+// Current, matching clientcmd's MinifyConfig. It returns an error if
+// Current is unset or does not match any context in c.
+func (c *KubeConfig) Minify() error {
+	if c.Current == "" {
+		return fmt.Errorf("k8s: minify: no current context set")
+	}
+	nctx := c.findContext(c.Current)
+	if nctx == nil || nctx.Context == nil {
+		return fmt.Errorf("k8s: minify: current context %q not found", c.Current)
+	}
+
+	var clusters []*NCluster
+	if ncl := c.findCluster(nctx.Context.Cluster); ncl != nil {
+		clusters = []*NCluster{ncl}
+	}
+	var users []*NUser
+	if nu := c.findUser(nctx.Context.User); nu != nil {
+		users = []*NUser{nu}
+	}
+
+	c.Clusters = clusters
+	c.Users = users
+	c.Contexts = []*NContext{nctx}
+	return nil
+}
+
+// Redact replaces the sensitive fields of every Cluster and User in c
+// (CertAuthority, Cert, Key, Token, Pass, AuthProvider.Config, and
+// ExecConfig.Env) with a "REDACTED" sentinel, matching clientcmd's
+// ShortenConfig. It mutates c in place and, unlike Minify, keeps every
+// entry. Run Redact before logging or printing a KubeConfig via String
+// or Write; AuthProvider and ExecConfig's own String/GoString already
+// redact themselves for fmt-based printing, but Config and Env commonly
+// carry the same cloud refresh tokens and plugin secrets, so they are
+// scrubbed here too.
+func (c *KubeConfig) Redact() {
+	const redacted = "REDACTED"
+	for _, n := range c.Clusters {
+		if n.Cluster == nil {
+			continue
+		}
+		if n.Cluster.CertAuthority != "" {
+			n.Cluster.CertAuthority = redacted
+		}
+	}
+	for _, n := range c.Users {
+		if n.User == nil {
+			continue
+		}
+		if n.User.Cert != "" {
+			n.User.Cert = redacted
+		}
+		if n.User.Key != "" {
+			n.User.Key = redacted
+		}
+		if n.User.Token != "" {
+			n.User.Token = redacted
+		}
+		if n.User.Pass != "" {
+			n.User.Pass = redacted
+		}
+		if n.User.Auth != nil {
+			for k := range n.User.Auth.Config {
+				n.User.Auth.Config[k] = redacted
+			}
+		}
+		if n.User.Exec != nil {
+			for i := range n.User.Exec.Env {
+				n.User.Exec.Env[i].Value = redacted
+			}
+		}
+	}
+}
+
+// mergeNamed appends to base every element of extra whose name is not
+// already present in base, giving base first-wins precedence.
+func mergeNamed[T any](base, extra []T, name func(T) string) []T {
+	seen := make(map[string]bool, len(base))
+	for _, v := range base {
+		seen[name(v)] = true
+	}
+	for _, v := range extra {
+		if n := name(v); !seen[n] {
+			base = append(base, v)
+			seen[n] = true
+		}
+	}
+	return base
+}
+
+// Merge combines other into c, matching Clusters, Users, and Contexts by
+// Name and keeping c's entry whenever both define one with the same
+// name. This is first-wins precedence, the same behavior KUBECONFIG
+// applies across a colon-separated chain of files (see LoadChain).
+// Current is only taken from other if c does not already set one.
+func (c *KubeConfig) Merge(other *KubeConfig) {
+	if other == nil {
+		return
+	}
+	c.Clusters = mergeNamed(c.Clusters, other.Clusters, func(n *NCluster) string { return n.Name })
+	c.Users = mergeNamed(c.Users, other.Users, func(n *NUser) string { return n.Name })
+	c.Contexts = mergeNamed(c.Contexts, other.Contexts, func(n *NContext) string { return n.Name })
+	if c.Current == "" {
+		c.Current = other.Current
+	}
+}
+
+// LoadChain loads and merges, in order, every kubeconfig found across
+// one or more colon-separated chains of paths such as the $KUBECONFIG
+// environment variable provides. Earlier paths take precedence over
+// later ones, matching KUBECONFIG's own merge semantics. Paths that
+// don't exist are silently skipped, matching clientcmd's loading rules,
+// since a chain like "$HOME/.kube/config:$HOME/.kube/config2" is
+// expected to work even when not every entry is present.
+func (c *KubeConfig) LoadChain(paths ...string) error {
+	for _, chain := range paths {
+		for _, path := range filepath.SplitList(chain) {
+			if path == "" {
+				continue
+			}
+			var next KubeConfig
+			if err := next.Load(path); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			c.Merge(&next)
+		}
+	}
+	return nil
+}
+
+// Embed reads every file referenced by a path-based credential field
+// across c (Cluster.CertAuthorityPath, User.CertPath, User.KeyPath),
+// base64-encodes its contents into the matching *-data field, and
+// clears the path field. This is the standard "flatten" operation
+// needed before shipping a kubeconfig to a machine that won't have
+// those files on disk. See Externalize for the inverse.
+func (c *KubeConfig) Embed() error {
+	for _, n := range c.Clusters {
+		if n.Cluster == nil || n.Cluster.CertAuthorityPath == "" {
+			continue
+		}
+		data, err := os.ReadFile(n.Cluster.CertAuthorityPath)
+		if err != nil {
+			return err
+		}
+		n.Cluster.CertAuthority = base64.StdEncoding.EncodeToString(data)
+		n.Cluster.CertAuthorityPath = ""
+	}
+	for _, n := range c.Users {
+		if n.User == nil {
+			continue
+		}
+		if n.User.CertPath != "" {
+			data, err := os.ReadFile(n.User.CertPath)
+			if err != nil {
+				return err
+			}
+			n.User.Cert = base64.StdEncoding.EncodeToString(data)
+			n.User.CertPath = ""
+		}
+		if n.User.KeyPath != "" {
+			data, err := os.ReadFile(n.User.KeyPath)
+			if err != nil {
+				return err
+			}
+			n.User.Key = base64.StdEncoding.EncodeToString(data)
+			n.User.KeyPath = ""
+		}
+	}
+	return nil
+}
+
+// Externalize decodes every *-data credential field across c, writes
+// its contents out to a file under dir named after the owning cluster
+// or user, and swaps the path-based field in for the data field. It is
+// the inverse of Embed.
+func (c *KubeConfig) Externalize(dir string) error {
+	for _, n := range c.Clusters {
+		if n.Cluster == nil || n.Cluster.CertAuthority == "" {
+			continue
+		}
+		path, err := writeDataFile(dir, n.Name, "ca.crt", n.Cluster.CertAuthority)
+		if err != nil {
+			return err
+		}
+		n.Cluster.CertAuthorityPath = path
+		n.Cluster.CertAuthority = ""
+	}
+	for _, n := range c.Users {
+		if n.User == nil {
+			continue
+		}
+		if n.User.Cert != "" {
+			path, err := writeDataFile(dir, n.Name, "crt", n.User.Cert)
+			if err != nil {
+				return err
+			}
+			n.User.CertPath = path
+			n.User.Cert = ""
+		}
+		if n.User.Key != "" {
+			path, err := writeDataFile(dir, n.Name, "key", n.User.Key)
+			if err != nil {
+				return err
+			}
+			n.User.KeyPath = path
+			n.User.Key = ""
+		}
+	}
+	return nil
+}
+
+// writeDataFile base64-decodes data and writes it to dir/name.suffix,
+// returning the path written.
+func writeDataFile(dir, name, suffix, data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s.%s", name, suffix))
+	if err := os.WriteFile(path, decoded, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Validate checks c for the usual ways a KUBECONFIG ends up
+// inconsistent and returns every problem found, joined via errors.Join
+// (nil if none): duplicate names within Clusters, Users, or Contexts;
+// contexts or Current referencing entries that don't exist; users
+// combining mutually exclusive auth methods (token, basic auth, exec,
+// auth-provider); clusters that set both CertAuthority(Path) and
+// SkipTLSVerify; and empty required fields such as Cluster.Server or
+// Context.Cluster.
+func (c *KubeConfig) Validate() error {
+	var errs []error
+
+	clusterNames := map[string]bool{}
+	for _, n := range c.Clusters {
+		if clusterNames[n.Name] {
+			errs = append(errs, fmt.Errorf("k8s: duplicate cluster name %q", n.Name))
+		}
+		clusterNames[n.Name] = true
+		if n.Cluster == nil {
+			continue
+		}
+		if n.Cluster.Server == "" {
+			errs = append(errs, fmt.Errorf("k8s: cluster %q: empty server", n.Name))
+		}
+		if (n.Cluster.CertAuthority != "" || n.Cluster.CertAuthorityPath != "") && n.Cluster.SkipTLSVerify {
+			errs = append(errs, fmt.Errorf(
+				"k8s: cluster %q: certificate-authority(-data) is set but insecure-skip-tls-verify is also true",
+				n.Name))
+		}
+	}
+
+	userNames := map[string]bool{}
+	for _, n := range c.Users {
+		if userNames[n.Name] {
+			errs = append(errs, fmt.Errorf("k8s: duplicate user name %q", n.Name))
+		}
+		userNames[n.Name] = true
+		if n.User == nil {
+			continue
+		}
+		methods := 0
+		if n.User.Token != "" {
+			methods++
+		}
+		if n.User.Name != "" || n.User.Pass != "" {
+			methods++
+		}
+		if n.User.Exec != nil {
+			methods++
+		}
+		if n.User.Auth != nil {
+			methods++
+		}
+		if methods > 1 {
+			errs = append(errs, fmt.Errorf(
+				"k8s: user %q: combines more than one mutually exclusive auth method (token, basic auth, exec, auth-provider)",
+				n.Name))
+		}
+	}
+
+	contextNames := map[string]bool{}
+	for _, n := range c.Contexts {
+		if contextNames[n.Name] {
+			errs = append(errs, fmt.Errorf("k8s: duplicate context name %q", n.Name))
+		}
+		contextNames[n.Name] = true
+		if n.Context == nil {
+			continue
+		}
+		if n.Context.Cluster == "" {
+			errs = append(errs, fmt.Errorf("k8s: context %q: empty cluster", n.Name))
+		} else if !clusterNames[n.Context.Cluster] {
+			errs = append(errs, fmt.Errorf("k8s: context %q: references unknown cluster %q", n.Name, n.Context.Cluster))
+		}
+		if n.Context.User != "" && !userNames[n.Context.User] {
+			errs = append(errs, fmt.Errorf("k8s: context %q: references unknown user %q", n.Name, n.Context.User))
+		}
+	}
+
+	if c.Current != "" && !contextNames[c.Current] {
+		errs = append(errs, fmt.Errorf("k8s: current-context %q not found", c.Current))
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveContext resolves contextName (empty meaning Current) to its
+// Cluster and User, returning an error if the context or its cluster
+// can't be found, or if it names a user that doesn't exist. A context
+// with no user set is not an error (Validate agrees) and resolves to a
+// zero-value *User, i.e. no credentials.
+func (c *KubeConfig) resolveContext(contextName string) (*Cluster, *User, error) {
+	if contextName == "" {
+		contextName = c.Current
+	}
+	if contextName == "" {
+		return nil, nil, fmt.Errorf("k8s: no context specified and no current-context set")
+	}
+	nctx := c.findContext(contextName)
+	if nctx == nil || nctx.Context == nil {
+		return nil, nil, fmt.Errorf("k8s: context %q not found", contextName)
+	}
+	ncl := c.findCluster(nctx.Context.Cluster)
+	if ncl == nil || ncl.Cluster == nil {
+		return nil, nil, fmt.Errorf("k8s: context %q references unknown cluster %q", contextName, nctx.Context.Cluster)
+	}
+	if nctx.Context.User == "" {
+		return ncl.Cluster, &User{}, nil
+	}
+	nu := c.findUser(nctx.Context.User)
+	if nu == nil || nu.User == nil {
+		return nil, nil, fmt.Errorf("k8s: context %q references unknown user %q", contextName, nctx.Context.User)
+	}
+	return ncl.Cluster, nu.User, nil
+}
+
+// decodeBase64 decodes s, returning nil with no error for an empty
+// string so callers can pass optional *-data fields straight through.
+func decodeBase64(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// RESTConfig resolves contextName (empty meaning Current) to its
+// cluster and user and returns a *rest.Config built from them: the
+// base64 CA/cert/key blobs are decoded, SkipTLSVerify, TLSServerName,
+// and Proxy are carried over, and whichever of bearer token, basic
+// auth, or exec-plugin credentials the user defines is wired up. See
+// Transport for a plain http.RoundTripper instead.
+func (c *KubeConfig) RESTConfig(contextName string) (*rest.Config, error) {
+	cluster, user, err := c.resolveContext(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	caData, err := decodeBase64(cluster.CertAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("k8s: decoding certificate-authority-data: %w", err)
+	}
+	certData, err := decodeBase64(user.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("k8s: decoding client-certificate-data: %w", err)
+	}
+	keyData, err := decodeBase64(user.Key)
+	if err != nil {
+		return nil, fmt.Errorf("k8s: decoding client-key-data: %w", err)
+	}
+
+	cfg := &rest.Config{
+		Host: cluster.Server,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure:   cluster.SkipTLSVerify,
+			ServerName: cluster.TLSServerName,
+			CAData:     caData,
+			CertData:   certData,
+			KeyData:    keyData,
+		},
+	}
+
+	if cluster.Proxy != "" {
+		proxyURL, err := url.Parse(cluster.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: parsing proxy-url: %w", err)
+		}
+		cfg.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	switch {
+	case user.Token != "":
+		cfg.BearerToken = user.Token
+	case user.Name != "" || user.Pass != "":
+		cfg.Username = user.Name
+		cfg.Password = user.Pass
+	case user.Exec != nil:
+		cred, err := user.RunExec(context.Background(), cluster)
+		if err != nil {
+			return nil, err
+		}
+		cfg.BearerToken = cred.Status.Token
+		if cred.Status.ClientCertificateData != "" {
+			cfg.TLSClientConfig.CertData = []byte(cred.Status.ClientCertificateData)
+		}
+		if cred.Status.ClientKeyData != "" {
+			cfg.TLSClientConfig.KeyData = []byte(cred.Status.ClientKeyData)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Transport resolves contextName (empty meaning Current) the same way
+// RESTConfig does and returns a ready-to-use http.RoundTripper built
+// from it, for callers that want a transport without a full *rest.Config.
+func (c *KubeConfig) Transport(contextName string) (http.RoundTripper, error) {
+	cfg, err := c.RESTConfig(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return rest.TransportFor(cfg)
+}
+
 // NCluster associates a name with a cluster.
 type NCluster struct {
-	Name    string
-	Cluster *Cluster
+	Name    string   `json:"name"`
+	Cluster *Cluster `json:"cluster"`
 }
 
 // Cluster contains information about a Kubernetes cluster.
 type Cluster struct {
-	Server        string         `yaml:"server"`
-	TLSServerName string         `yaml:"tls-server-name,omitempty"`
-	SkipTLSVerify bool           `yaml:"insecure-skip-tls-verify,omitempty"`
-	CertAuthority string         `yaml:"certificate-authority-data,omitempty"`
-	Proxy         string         `yaml:"proxy-url,omitempty"`
-	O             map[string]any `yaml:",inline,omitempty"`
+	Server            string         `json:"server"`
+	TLSServerName     string         `json:"tls-server-name,omitempty"`
+	SkipTLSVerify     bool           `json:"insecure-skip-tls-verify,omitempty"`
+	CertAuthority     string         `json:"certificate-authority-data,omitempty"`
+	CertAuthorityPath string         `json:"certificate-authority,omitempty"`
+	Proxy             string         `json:"proxy-url,omitempty"`
+	O                 map[string]any `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining O.
+func (c Cluster) MarshalJSON() ([]byte, error) {
+	type alias Cluster
+	return marshalInline(alias(c), c.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown keys
+// into O.
+func (c *Cluster) UnmarshalJSON(data []byte) error {
+	type alias Cluster
+	a := alias(*c)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*c = Cluster(a)
+	c.O = extra
+	return nil
 }
 
 // NUser associates a name with a user.
 type NUser struct {
-	Name string
-	User *User
+	Name string `json:"name"`
+	User *User  `json:"user"`
 }
 
 // User (officially "AuthInfo") contains information that describes
@@ -108,37 +704,100 @@ type NUser struct {
 // resides within a single KUBECONFIG file. Names have been shortened to
 // reasonable lengths.
 type User struct {
-	Cert     string         `yaml:"client-certificate-data,omitempty"`
-	Key      string         `yaml:"client-key-data,omitempty"`
-	Token    string         `yaml:"token,omitempty"`
-	As       string         `yaml:"act-as,omitempty"`
-	AsUID    string         `yaml:"act-as-uid,omitempty"`
-	AsGroups []string       `yaml:"act-as-groups,omitempty"`
-	Name     string         `yaml:"username,omitempty"`
-	Pass     string         `yaml:"password,omitempty"`
-	Auth     *AuthProvider  `yaml:"auth-provider,omitempty"`
-	O        map[string]any `yaml:",inline,omitempty"`
+	Cert     string         `json:"client-certificate-data,omitempty"`
+	CertPath string         `json:"client-certificate,omitempty"`
+	Key      string         `json:"client-key-data,omitempty"`
+	KeyPath  string         `json:"client-key,omitempty"`
+	Token    string         `json:"token,omitempty"`
+	As       string         `json:"act-as,omitempty"`
+	AsUID    string         `json:"act-as-uid,omitempty"`
+	AsGroups []string       `json:"act-as-groups,omitempty"`
+	Name     string         `json:"username,omitempty"`
+	Pass     string         `json:"password,omitempty"`
+	Auth     *AuthProvider  `json:"auth-provider,omitempty"`
+	Exec     *ExecConfig    `json:"exec,omitempty"`
+	O        map[string]any `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining O.
+func (u User) MarshalJSON() ([]byte, error) {
+	type alias User
+	return marshalInline(alias(u), u.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown keys
+// into O.
+func (u *User) UnmarshalJSON(data []byte) error {
+	type alias User
+	a := alias(*u)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*u = User(a)
+	u.O = extra
+	return nil
 }
 
 // NContext associates a name with a context.
 type NContext struct {
-	Name    string
-	Context *Context
+	Name    string   `json:"name"`
+	Context *Context `json:"context"`
 }
 
 // Context is mostly cluster, user, and namespace.
 type Context struct {
-	Cluster   string         `yaml:"cluster"`
-	User      string         `yaml:"user"`
-	Namespace string         `yaml:"namespace,omitempty"`
-	O         map[string]any `yaml:",inline,omitempty"`
+	Cluster   string         `json:"cluster"`
+	User      string         `json:"user"`
+	Namespace string         `json:"namespace,omitempty"`
+	O         map[string]any `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining O.
+func (c Context) MarshalJSON() ([]byte, error) {
+	type alias Context
+	return marshalInline(alias(c), c.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown keys
+// into O.
+func (c *Context) UnmarshalJSON(data []byte) error {
+	type alias Context
+	a := alias(*c)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*c = Context(a)
+	c.O = extra
+	return nil
 }
 
 // AuthProvider holds the configuration for a specified auth provider.
 type AuthProvider struct {
-	Name   string            `yaml:"name"`
-	Config map[string]string `yaml:"config,omitempty"`
-	O      map[string]any    `yaml:",inline,omitempty"`
+	Name   string            `json:"name"`
+	Config map[string]string `json:"config,omitempty"`
+	O      map[string]any    `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining O.
+func (c AuthProvider) MarshalJSON() ([]byte, error) {
+	type alias AuthProvider
+	return marshalInline(alias(c), c.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown keys
+// into O.
+func (c *AuthProvider) UnmarshalJSON(data []byte) error {
+	type alias AuthProvider
+	a := alias(*c)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*c = AuthProvider(a)
+	c.O = extra
+	return nil
 }
 
 // GoString implements fmt.GoStringer and sanitizes sensitive fields of
@@ -155,3 +814,151 @@ func (c AuthProvider) String() string {
 	return fmt.Sprintf("api.AuthProvider{Name: %q, Config: map[string]string{%s}}",
 		c.Name, cfg)
 }
+
+// ExecConfig holds the configuration for an exec credential plugin, the
+// mechanism most cloud providers and OIDC helpers now use in place of
+// AuthProvider to hand out short-lived credentials. See RunExec and
+// https://kubernetes.io/docs/reference/access-authn-authz/authentication/#client-go-credential-plugins.
+type ExecConfig struct {
+	Command            string         `json:"command"`
+	Args               []string       `json:"args,omitempty"`
+	Env                []ExecEnvVar   `json:"env,omitempty"`
+	APIVersion         string         `json:"apiVersion,omitempty"`
+	InstallHint        string         `json:"installHint,omitempty"`
+	ProvideClusterInfo bool           `json:"provideClusterInfo,omitempty"`
+	InteractiveMode    string         `json:"interactiveMode,omitempty"`
+	O                  map[string]any `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, inlining O.
+func (c ExecConfig) MarshalJSON() ([]byte, error) {
+	type alias ExecConfig
+	return marshalInline(alias(c), c.O)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown keys
+// into O.
+func (c *ExecConfig) UnmarshalJSON(data []byte) error {
+	type alias ExecConfig
+	a := alias(*c)
+	extra, err := unmarshalInline(data, &a)
+	if err != nil {
+		return err
+	}
+	*c = ExecConfig(a)
+	c.O = extra
+	return nil
+}
+
+// GoString implements fmt.GoStringer and sanitizes Env of ExecConfig to
+// prevent accidental leaking via logs (exec plugins routinely pass
+// secrets through the environment).
+func (c ExecConfig) GoString() string { return c.String() }
+
+// String implements fmt.Stringer and sanitizes Env of ExecConfig to
+// prevent accidental leaking via logs.
+func (c ExecConfig) String() string {
+	env := "[]"
+	if len(c.Env) > 0 {
+		env = "--- REDACTED ---"
+	}
+	return fmt.Sprintf("api.ExecConfig{Command: %q, Args: %q, Env: %s}",
+		c.Command, c.Args, env)
+}
+
+// ExecEnvVar is a name/value pair passed to the exec plugin process in
+// addition to the current environment.
+type ExecEnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ExecCredential is the request sent to, and response read back from, an
+// exec credential plugin over stdin/stdout as defined by the
+// client.authentication.k8s.io API. RunExec marshals the request half
+// and parses the response half of this same type.
+type ExecCredential struct {
+	Kind       string                `json:"kind,omitempty"`
+	APIVersion string                `json:"apiVersion,omitempty"`
+	Spec       ExecCredentialSpec    `json:"spec,omitempty"`
+	Status     *ExecCredentialStatus `json:"status,omitempty"`
+}
+
+// ExecCredentialSpec is the input half of ExecCredential, describing the
+// cluster the plugin is fetching credentials for.
+type ExecCredentialSpec struct {
+	Cluster     *ExecCluster `json:"cluster,omitempty"`
+	Interactive bool         `json:"interactive,omitempty"`
+}
+
+// ExecCluster is the subset of Cluster forwarded to an exec plugin when
+// ExecConfig.ProvideClusterInfo is true.
+type ExecCluster struct {
+	Server                   string `json:"server"`
+	TLSServerName            string `json:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthorityData string `json:"certificate-authority-data,omitempty"`
+	ProxyURL                 string `json:"proxy-url,omitempty"`
+}
+
+// ExecCredentialStatus is the output half of ExecCredential, containing
+// the credential the plugin obtained and when it expires.
+type ExecCredentialStatus struct {
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+}
+
+// RunExec executes the plugin configured in User.Exec, writing an
+// ExecCredential request to its stdin and parsing the ExecCredential
+// response from its stdout, per the client.authentication.k8s.io
+// protocol. cluster may be nil; it is only consulted (and only sent to
+// the plugin) when Exec.ProvideClusterInfo is set.
+func (u *User) RunExec(ctx context.Context, cluster *Cluster) (*ExecCredential, error) {
+	if u.Exec == nil {
+		return nil, fmt.Errorf("k8s: user has no exec configuration")
+	}
+
+	req := &ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: u.Exec.APIVersion,
+	}
+	if u.Exec.ProvideClusterInfo && cluster != nil {
+		req.Spec.Cluster = &ExecCluster{
+			Server:                   cluster.Server,
+			TLSServerName:            cluster.TLSServerName,
+			InsecureSkipTLSVerify:    cluster.SkipTLSVerify,
+			CertificateAuthorityData: cluster.CertAuthority,
+			ProxyURL:                 cluster.Proxy,
+		}
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, u.Exec.Command, u.Exec.Args...)
+	cmd.Env = os.Environ()
+	for _, v := range u.Exec.Env {
+		cmd.Env = append(cmd.Env, v.Name+"="+v.Value)
+	}
+	cmd.Stdin = bytes.NewReader(in)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("k8s: exec plugin %q: %w", u.Exec.Command, err)
+	}
+
+	resp := new(ExecCredential)
+	if err := json.Unmarshal(out.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("k8s: parsing exec credential from %q: %w", u.Exec.Command, err)
+	}
+	if resp.Status == nil {
+		return nil, fmt.Errorf("k8s: exec plugin %q returned no status", u.Exec.Command)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,310 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMergeFirstWinsPrecedence mirrors client-go's own
+// federal-context/cow-cluster/red-user clientcmd merge example: two
+// kubeconfigs define overlapping names, and the receiver of Merge keeps
+// its own entries while gaining whatever the other config adds.
+func TestMergeFirstWinsPrecedence(t *testing.T) {
+	primary := &KubeConfig{
+		Clusters: []*NCluster{{Name: "cow-cluster", Cluster: &Cluster{Server: "https://cow.example.com"}}},
+		Users:    []*NUser{{Name: "red-user", User: &User{Token: "red-token"}}},
+		Contexts: []*NContext{{Name: "federal-context", Context: &Context{Cluster: "cow-cluster", User: "red-user"}}},
+		Current:  "federal-context",
+	}
+	secondary := &KubeConfig{
+		Clusters: []*NCluster{
+			{Name: "cow-cluster", Cluster: &Cluster{Server: "https://should-not-win.example.com"}},
+			{Name: "horse-cluster", Cluster: &Cluster{Server: "https://horse.example.com"}},
+		},
+		Users: []*NUser{{Name: "red-user", User: &User{Token: "should-not-win"}}},
+		Contexts: []*NContext{
+			{Name: "federal-context", Context: &Context{Cluster: "should-not-win", User: "should-not-win"}},
+			{Name: "queen-anne-context", Context: &Context{Cluster: "horse-cluster", User: "red-user"}},
+		},
+		Current: "queen-anne-context",
+	}
+
+	primary.Merge(secondary)
+
+	if got := len(primary.Clusters); got != 2 {
+		t.Fatalf("expected 2 clusters after merge, got %d", got)
+	}
+	if c := primary.findCluster("cow-cluster"); c == nil || c.Cluster.Server != "https://cow.example.com" {
+		t.Errorf("primary cow-cluster should win merge, got %+v", c)
+	}
+	if c := primary.findCluster("horse-cluster"); c == nil || c.Cluster.Server != "https://horse.example.com" {
+		t.Errorf("expected horse-cluster to be added from secondary, got %+v", c)
+	}
+	if u := primary.findUser("red-user"); u == nil || u.User.Token != "red-token" {
+		t.Errorf("primary red-user should win merge, got %+v", u)
+	}
+	if ctx := primary.findContext("federal-context"); ctx == nil || ctx.Context.Cluster != "cow-cluster" {
+		t.Errorf("primary federal-context should win merge, got %+v", ctx)
+	}
+	if ctx := primary.findContext("queen-anne-context"); ctx == nil || ctx.Context.Cluster != "horse-cluster" {
+		t.Errorf("expected queen-anne-context to be added from secondary, got %+v", ctx)
+	}
+	if primary.Current != "federal-context" {
+		t.Errorf("primary Current should win merge, got %q", primary.Current)
+	}
+}
+
+func TestLoadChainSkipsMissingPaths(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "config")
+	missing := filepath.Join(dir, "does-not-exist")
+
+	present := &KubeConfig{
+		Clusters: []*NCluster{{Name: "cow-cluster", Cluster: &Cluster{Server: "https://cow.example.com"}}},
+		Current:  "federal-context",
+	}
+	if err := present.Write(real); err != nil {
+		t.Fatalf("writing fixture kubeconfig: %v", err)
+	}
+
+	c := &KubeConfig{}
+	if err := c.LoadChain(real + string(filepath.ListSeparator) + missing); err != nil {
+		t.Fatalf("LoadChain: %v", err)
+	}
+	if cl := c.findCluster("cow-cluster"); cl == nil || cl.Cluster.Server != "https://cow.example.com" {
+		t.Errorf("expected cow-cluster to be loaded from the real path, got %+v", cl)
+	}
+	if c.Current != "federal-context" {
+		t.Errorf("expected Current from the real path, got %q", c.Current)
+	}
+}
+
+func TestMinifyDanglingCurrent(t *testing.T) {
+	empty := &KubeConfig{}
+	if err := empty.Minify(); err == nil {
+		t.Error("expected error for unset current-context, got nil")
+	}
+
+	dangling := &KubeConfig{Current: "no-such-context"}
+	if err := dangling.Minify(); err == nil {
+		t.Error("expected error for dangling current-context, got nil")
+	}
+}
+
+func TestMinify(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{
+			{Name: "cow-cluster", Cluster: &Cluster{Server: "https://cow.example.com"}},
+			{Name: "horse-cluster", Cluster: &Cluster{Server: "https://horse.example.com"}},
+		},
+		Users: []*NUser{
+			{Name: "red-user", User: &User{Token: "red-token"}},
+			{Name: "blue-user", User: &User{Token: "blue-token"}},
+		},
+		Contexts: []*NContext{
+			{Name: "federal-context", Context: &Context{Cluster: "cow-cluster", User: "red-user"}},
+			{Name: "queen-anne-context", Context: &Context{Cluster: "horse-cluster", User: "blue-user"}},
+		},
+		Current: "federal-context",
+	}
+
+	if err := c.Minify(); err != nil {
+		t.Fatalf("Minify: %v", err)
+	}
+	if len(c.Clusters) != 1 || c.Clusters[0].Name != "cow-cluster" {
+		t.Errorf("expected only cow-cluster to remain, got %+v", c.Clusters)
+	}
+	if len(c.Users) != 1 || c.Users[0].Name != "red-user" {
+		t.Errorf("expected only red-user to remain, got %+v", c.Users)
+	}
+	if len(c.Contexts) != 1 || c.Contexts[0].Name != "federal-context" {
+		t.Errorf("expected only federal-context to remain, got %+v", c.Contexts)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{{Name: "c", Cluster: &Cluster{CertAuthority: "cadata"}}},
+		Users: []*NUser{{Name: "u", User: &User{
+			Cert:  "certdata",
+			Key:   "keydata",
+			Token: "tok",
+			Pass:  "pw",
+			Auth:  &AuthProvider{Name: "gcp", Config: map[string]string{"access-token": "secret"}},
+			Exec:  &ExecConfig{Command: "helper", Env: []ExecEnvVar{{Name: "TOKEN", Value: "secret"}}},
+		}}},
+	}
+
+	c.Redact()
+
+	cl := c.findCluster("c")
+	if cl.Cluster.CertAuthority != "REDACTED" {
+		t.Errorf("CertAuthority not redacted: %q", cl.Cluster.CertAuthority)
+	}
+	u := c.findUser("u")
+	for name, got := range map[string]string{
+		"Cert": u.User.Cert, "Key": u.User.Key, "Token": u.User.Token, "Pass": u.User.Pass,
+	} {
+		if got != "REDACTED" {
+			t.Errorf("%s not redacted: %q", name, got)
+		}
+	}
+	if got := u.User.Auth.Config["access-token"]; got != "REDACTED" {
+		t.Errorf("AuthProvider.Config not redacted: %q", got)
+	}
+	if got := u.User.Exec.Env[0].Value; got != "REDACTED" {
+		t.Errorf("ExecConfig.Env not redacted: %q", got)
+	}
+}
+
+func TestEmbedExternalizeRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	caPath := filepath.Join(src, "ca.pem")
+	certPath := filepath.Join(src, "client.pem")
+	keyPath := filepath.Join(src, "client-key.pem")
+	for path, content := range map[string]string{caPath: "ca-bytes", certPath: "cert-bytes", keyPath: "key-bytes"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("writing %s: %v", path, err)
+		}
+	}
+
+	c := &KubeConfig{
+		Clusters: []*NCluster{{Name: "c", Cluster: &Cluster{CertAuthorityPath: caPath}}},
+		Users:    []*NUser{{Name: "u", User: &User{CertPath: certPath, KeyPath: keyPath}}},
+	}
+
+	if err := c.Embed(); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	cl := c.findCluster("c")
+	if cl.Cluster.CertAuthorityPath != "" {
+		t.Errorf("expected CertAuthorityPath cleared after Embed, got %q", cl.Cluster.CertAuthorityPath)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(cl.Cluster.CertAuthority)
+	if err != nil || string(decoded) != "ca-bytes" {
+		t.Errorf("CertAuthority not embedded correctly: %q (%v)", cl.Cluster.CertAuthority, err)
+	}
+
+	u := c.findUser("u")
+	if u.User.CertPath != "" || u.User.KeyPath != "" {
+		t.Errorf("expected User path fields cleared after Embed, got CertPath=%q KeyPath=%q", u.User.CertPath, u.User.KeyPath)
+	}
+
+	out := t.TempDir()
+	if err := c.Externalize(out); err != nil {
+		t.Fatalf("Externalize: %v", err)
+	}
+
+	cl = c.findCluster("c")
+	if cl.Cluster.CertAuthority != "" {
+		t.Errorf("expected CertAuthority cleared after Externalize, got %q", cl.Cluster.CertAuthority)
+	}
+	data, err := os.ReadFile(cl.Cluster.CertAuthorityPath)
+	if err != nil {
+		t.Fatalf("reading externalized ca file: %v", err)
+	}
+	if string(data) != "ca-bytes" {
+		t.Errorf("externalized ca file content mismatch: %q", data)
+	}
+
+	u = c.findUser("u")
+	if u.User.Cert != "" || u.User.Key != "" {
+		t.Errorf("expected User data fields cleared after Externalize, got Cert=%q Key=%q", u.User.Cert, u.User.Key)
+	}
+	if data, err := os.ReadFile(u.User.CertPath); err != nil || string(data) != "cert-bytes" {
+		t.Errorf("externalized cert file mismatch: %q (%v)", data, err)
+	}
+	if data, err := os.ReadFile(u.User.KeyPath); err != nil || string(data) != "key-bytes" {
+		t.Errorf("externalized key file mismatch: %q (%v)", data, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{
+			{Name: "dup", Cluster: &Cluster{Server: "https://a"}},
+			{Name: "dup", Cluster: &Cluster{Server: "https://b"}},
+		},
+		Users: []*NUser{
+			{Name: "u", User: &User{Token: "tok", Pass: "pw"}},
+		},
+		Contexts: []*NContext{
+			{Name: "ctx", Context: &Context{Cluster: "missing-cluster", User: "missing-user"}},
+		},
+		Current: "missing-context",
+	}
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected Validate to return an error")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`duplicate cluster name "dup"`,
+		`references unknown cluster "missing-cluster"`,
+		`references unknown user "missing-user"`,
+		`mutually exclusive auth method`,
+		`current-context "missing-context" not found`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to mention %q, got: %s", want, msg)
+		}
+	}
+}
+
+func TestValidateSkipTLSVerifyContradiction(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{
+			{Name: "data", Cluster: &Cluster{Server: "https://a", CertAuthority: "abc", SkipTLSVerify: true}},
+			{Name: "path", Cluster: &Cluster{Server: "https://b", CertAuthorityPath: "/tmp/ca.pem", SkipTLSVerify: true}},
+		},
+	}
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected Validate to flag certificate-authority + skip-tls-verify contradictions")
+	}
+	if got := strings.Count(err.Error(), "insecure-skip-tls-verify is also true"); got != 2 {
+		t.Errorf("expected 2 contradictions flagged, got %d: %v", got, err)
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{{Name: "cow-cluster", Cluster: &Cluster{Server: "https://cow.example.com"}}},
+		Users:    []*NUser{{Name: "red-user", User: &User{Token: "red-token"}}},
+		Contexts: []*NContext{{Name: "federal-context", Context: &Context{Cluster: "cow-cluster", User: "red-user"}}},
+		Current:  "federal-context",
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected a clean KubeConfig to validate, got: %v", err)
+	}
+}
+
+func TestRESTConfigContextWithNoUser(t *testing.T) {
+	c := &KubeConfig{
+		Clusters: []*NCluster{{Name: "cow-cluster", Cluster: &Cluster{Server: "https://cow.example.com"}}},
+		Contexts: []*NContext{{Name: "anonymous-context", Context: &Context{Cluster: "cow-cluster"}}},
+		Current:  "anonymous-context",
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected a context with no user to validate, got: %v", err)
+	}
+
+	cfg, err := c.RESTConfig("")
+	if err != nil {
+		t.Fatalf("RESTConfig: %v", err)
+	}
+	if cfg.Host != "https://cow.example.com" {
+		t.Errorf("expected Host from cluster, got %q", cfg.Host)
+	}
+	if cfg.BearerToken != "" || cfg.Username != "" || cfg.Password != "" {
+		t.Errorf("expected no credentials for a context with no user, got %+v", cfg)
+	}
+}